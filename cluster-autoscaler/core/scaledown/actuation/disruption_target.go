@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+// disruptionTargetReasonEmpty and disruptionTargetReasonUnderutilized describe why CA is disrupting the pod's
+// node, mirroring the terminology the scale-down planner already uses for the two kinds of removable nodes.
+const (
+	disruptionTargetReasonEmpty         = "empty"
+	disruptionTargetReasonUnderutilized = "underutilized"
+
+	// disruptionTargetReasonEvictedByCA is the Reason set on the DisruptionTarget condition, per KEP-3329.
+	disruptionTargetReasonEvictedByCA = "EvictionByClusterAutoscaler"
+)
+
+// maybeEmitDisruptionTargetCondition patches pod's status to add a DisruptionTarget condition, if
+// e.EmitDisruptionTargetCondition is set, before pod is handed off for eviction. This gives workload controllers
+// (Jobs, StatefulSets, custom operators) a reliable signal that the pod is being terminated by CA rather than
+// crashing (KEP-3329). Failures are logged and otherwise ignored: the eviction proceeds either way.
+func (e *Evictor) maybeEmitDisruptionTargetCondition(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, node *apiv1.Node, empty bool) {
+	if !e.EmitDisruptionTargetCondition {
+		return
+	}
+	reason := disruptionTargetReasonUnderutilized
+	if empty {
+		reason = disruptionTargetReasonEmpty
+	}
+	nodeGroup := "unknown"
+	if ctx.CloudProvider != nil {
+		if ng, err := ctx.CloudProvider.NodeGroupForNode(node); err == nil && ng != nil {
+			nodeGroup = ng.Id()
+		}
+	}
+	message := fmt.Sprintf("Cluster Autoscaler is removing node %s (%s) and evicting this pod", node.Name, reason)
+
+	current, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+	if err != nil {
+		klog.Warningf("Not setting DisruptionTarget condition on pod %s/%s, failed to fetch it: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+	current.Status.Conditions = append(current.Status.Conditions, apiv1.PodCondition{
+		Type:    apiv1.DisruptionTarget,
+		Status:  apiv1.ConditionTrue,
+		Reason:  disruptionTargetReasonEvictedByCA,
+		Message: fmt.Sprintf("%s (node group: %s)", message, nodeGroup),
+	})
+	if _, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).UpdateStatus(context.TODO(), current, metav1.UpdateOptions{}); err != nil {
+		klog.Warningf("Not setting DisruptionTarget condition on pod %s/%s, failed to patch its status: %v", pod.Namespace, pod.Name, err)
+	}
+}
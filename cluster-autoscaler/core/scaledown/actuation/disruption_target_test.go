@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestMaybeEmitDisruptionTargetCondition(t *testing.T) {
+	for tn, tc := range map[string]struct {
+		emit          bool
+		empty         bool
+		getErr        error
+		updateErr     error
+		wantCondition bool
+		wantReason    string
+	}{
+		"disabled does nothing": {
+			emit:          false,
+			wantCondition: false,
+		},
+		"empty node sets the empty reason": {
+			emit:          true,
+			empty:         true,
+			wantCondition: true,
+			wantReason:    disruptionTargetReasonEmpty,
+		},
+		"underutilized node sets the underutilized reason": {
+			emit:          true,
+			empty:         false,
+			wantCondition: true,
+			wantReason:    disruptionTargetReasonUnderutilized,
+		},
+		"a failed Get is logged, not fatal": {
+			emit:          true,
+			getErr:        fmt.Errorf("get failed"),
+			wantCondition: false,
+		},
+		"a failed UpdateStatus is logged, not fatal": {
+			emit:          true,
+			updateErr:     fmt.Errorf("update failed"),
+			wantCondition: false,
+		},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			pod := BuildTestPod("p1", 100, 0)
+			node := BuildTestNode("n1", 1000, 1000)
+
+			// patched is the pod as stored by an update the fake API server accepted. Actions() can't be used for
+			// this, since the fake records a rejected update there too.
+			var patched *apiv1.Pod
+			fakeClient := &fake.Clientset{}
+			fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				if tc.getErr != nil {
+					return true, nil, tc.getErr
+				}
+				return true, pod, nil
+			})
+			fakeClient.Fake.AddReactor("update", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				if tc.updateErr != nil {
+					return true, nil, tc.updateErr
+				}
+				patched = action.(core.UpdateAction).GetObject().(*apiv1.Pod)
+				return true, patched, nil
+			})
+
+			ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, fakeClient, nil, nil, nil, nil)
+			assert.NoError(t, err)
+
+			evictor := Evictor{EmitDisruptionTargetCondition: tc.emit}
+			evictor.maybeEmitDisruptionTargetCondition(&ctx, pod, node, tc.empty)
+
+			if !tc.wantCondition {
+				assert.Nil(t, patched)
+				return
+			}
+			if assert.NotNil(t, patched) {
+				cond := patched.Status.Conditions[len(patched.Status.Conditions)-1]
+				assert.Equal(t, apiv1.DisruptionTarget, cond.Type)
+				assert.Equal(t, apiv1.ConditionTrue, cond.Status)
+				assert.Equal(t, disruptionTargetReasonEvictedByCA, cond.Reason)
+				assert.Contains(t, cond.Message, tc.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+// DoNotEvictPolicy controls how Evictor treats pods carrying a do-not-evict annotation.
+type DoNotEvictPolicy string
+
+const (
+	// DoNotEvictBlock is the default: a node with a do-not-evict pod on it is never picked as a scale-down
+	// candidate in the first place, so DrainNodeWithPods should never see one in practice. If it does, the pod
+	// is marked Blocked rather than evicted.
+	DoNotEvictBlock DoNotEvictPolicy = "Block"
+	// DoNotEvictWaitAndEvict waits up to DoNotEvictGracePeriod for the annotation to clear, then evicts
+	// regardless, so a pod that never clears the annotation doesn't hang the drain forever.
+	DoNotEvictWaitAndEvict DoNotEvictPolicy = "WaitAndEvict"
+	// DoNotEvictForce waits out DoNotEvictGracePeriod and then evicts unconditionally, skipping the early-exit
+	// WaitAndEvict gets when the annotation clears before the deadline.
+	DoNotEvictForce DoNotEvictPolicy = "Force"
+)
+
+// doNotEvictSafeToEvictAnnotation is the built-in annotation honored regardless of DoNotEvictAnnotations.
+const doNotEvictSafeToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+
+var doNotEvictStalledDrains = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "cluster_autoscaler",
+	Name:      "do_not_evict_stalled_drains_total",
+	Help:      "Number of pod drains that stalled because the pod carries a do-not-evict annotation.",
+})
+
+func init() {
+	prometheus.MustRegister(doNotEvictStalledDrains)
+}
+
+// handleDoNotEvict applies e.DoNotEvictPolicy to pod and reports whether the pod should be skipped (Blocked)
+// rather than handed to the eviction queue.
+func (e *Evictor) handleDoNotEvict(ctx *acontext.AutoscalingContext, node *apiv1.Node, pod *apiv1.Pod) bool {
+	if !hasDoNotEvictAnnotation(pod, e.DoNotEvictAnnotations) {
+		return false
+	}
+
+	switch e.DoNotEvictPolicy {
+	case DoNotEvictWaitAndEvict:
+		deadline := time.Now().Add(e.DoNotEvictGracePeriod)
+		for hasDoNotEvictAnnotation(pod, e.DoNotEvictAnnotations) {
+			if !time.Now().Before(deadline) {
+				// The annotation never cleared, but WaitAndEvict evicts anyway rather than blocking forever.
+				return false
+			}
+			time.Sleep(confirmationPollInterval)
+			refreshed, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if err != nil {
+				// The pod is gone or unreachable; nothing left to block on.
+				return false
+			}
+			pod = refreshed
+		}
+		return false
+	case DoNotEvictForce:
+		// Unlike WaitAndEvict, Force doesn't re-check the annotation or exit early if it clears: it always waits
+		// out the full grace period before evicting, regardless of the pod's state.
+		time.Sleep(e.DoNotEvictGracePeriod)
+		return false
+	case DoNotEvictBlock:
+		fallthrough
+	default:
+		return true
+	}
+}
+
+// hasDoNotEvictAnnotation reports whether pod carries the built-in safe-to-evict=false annotation or one of the
+// operator-configured extraAnnotations set to "false".
+func hasDoNotEvictAnnotation(pod *apiv1.Pod, extraAnnotations []string) bool {
+	if pod.Annotations[doNotEvictSafeToEvictAnnotation] == "false" {
+		return true
+	}
+	for _, key := range extraAnnotations {
+		if pod.Annotations[key] == "false" {
+			return true
+		}
+	}
+	return false
+}
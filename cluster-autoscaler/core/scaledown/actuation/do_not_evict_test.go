@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestHandleDoNotEvict(t *testing.T) {
+	for tn, tc := range map[string]struct {
+		annotations      map[string]string
+		extraAnnotations []string
+		policy           DoNotEvictPolicy
+		gracePeriod      time.Duration
+		getReactor       func(action core.Action) (bool, runtime.Object, error)
+		wantBlocked      bool
+	}{
+		"no annotation is never blocked, regardless of policy": {
+			policy:      DoNotEvictBlock,
+			wantBlocked: false,
+		},
+		"built-in annotation blocks under Block": {
+			annotations: map[string]string{doNotEvictSafeToEvictAnnotation: "false"},
+			policy:      DoNotEvictBlock,
+			wantBlocked: true,
+		},
+		"extra annotation blocks under Block": {
+			annotations:      map[string]string{"example.com/do-not-evict": "false"},
+			extraAnnotations: []string{"example.com/do-not-evict"},
+			policy:           DoNotEvictBlock,
+			wantBlocked:      true,
+		},
+		"unset policy defaults to Block": {
+			annotations: map[string]string{doNotEvictSafeToEvictAnnotation: "false"},
+			wantBlocked: true,
+		},
+		"WaitAndEvict evicts once the annotation clears": {
+			annotations: map[string]string{doNotEvictSafeToEvictAnnotation: "false"},
+			policy:      DoNotEvictWaitAndEvict,
+			gracePeriod: time.Second,
+			getReactor: func(action core.Action) (bool, runtime.Object, error) {
+				return true, BuildTestPod("p1", 100, 0), nil
+			},
+			wantBlocked: false,
+		},
+		"WaitAndEvict evicts anyway once the grace period elapses": {
+			annotations: map[string]string{doNotEvictSafeToEvictAnnotation: "false"},
+			policy:      DoNotEvictWaitAndEvict,
+			gracePeriod: 0,
+			wantBlocked: false,
+		},
+		"Force evicts unconditionally once the grace period elapses": {
+			annotations: map[string]string{doNotEvictSafeToEvictAnnotation: "false"},
+			policy:      DoNotEvictForce,
+			gracePeriod: 0,
+			wantBlocked: false,
+		},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			fakeClient := &fake.Clientset{}
+			if tc.getReactor != nil {
+				fakeClient.Fake.AddReactor("get", "pods", tc.getReactor)
+			}
+
+			pod := BuildTestPod("p1", 100, 0)
+			pod.Annotations = tc.annotations
+			node := BuildTestNode("n1", 1000, 1000)
+
+			ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, fakeClient, nil, nil, nil, nil)
+			assert.NoError(t, err)
+
+			evictor := Evictor{
+				DoNotEvictPolicy:      tc.policy,
+				DoNotEvictAnnotations: tc.extraAnnotations,
+				DoNotEvictGracePeriod: tc.gracePeriod,
+			}
+			blocked := evictor.handleDoNotEvict(&ctx, node, pod)
+			assert.Equal(t, tc.wantBlocked, blocked)
+		})
+	}
+}
+
+func TestHasDoNotEvictAnnotation(t *testing.T) {
+	pod := BuildTestPod("p1", 100, 0)
+
+	assert.False(t, hasDoNotEvictAnnotation(pod, nil))
+
+	pod.Annotations = map[string]string{doNotEvictSafeToEvictAnnotation: "false"}
+	assert.True(t, hasDoNotEvictAnnotation(pod, nil))
+
+	pod.Annotations = map[string]string{doNotEvictSafeToEvictAnnotation: "true"}
+	assert.False(t, hasDoNotEvictAnnotation(pod, nil))
+
+	pod.Annotations = map[string]string{"example.com/do-not-evict": "false"}
+	assert.False(t, hasDoNotEvictAnnotation(pod, nil))
+	assert.True(t, hasDoNotEvictAnnotation(pod, []string{"example.com/do-not-evict"}))
+}
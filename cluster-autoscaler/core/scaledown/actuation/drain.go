@@ -0,0 +1,377 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+)
+
+const (
+	// DefaultPodEvictionHeadroom is the extra time we wait after MaxPodEvictionTime to confirm that an evicted pod
+	// has actually disappeared (or been rescheduled elsewhere), to cover pods that ignore SIGTERM for a while.
+	DefaultPodEvictionHeadroom = 30 * time.Second
+	// DefaultMaxConcurrentEvictions is the number of eviction workers an Evictor starts if MaxConcurrentEvictions
+	// isn't set, bounding how many pods across every node draining in parallel hit the API server at once.
+	DefaultMaxConcurrentEvictions = 10
+	// maxEvictionBackoff caps the exponential backoff applied to a pod whose eviction keeps failing transiently.
+	maxEvictionBackoff = 30 * time.Second
+	// confirmationPollInterval is how often we re-check whether an evicted pod has actually disappeared.
+	confirmationPollInterval = 250 * time.Millisecond
+)
+
+// EvictionResult records the outcome of evicting a single pod. Err is set if the eviction call failed, or if the
+// call succeeded but every lookup confirming the pod's removal failed until the deadline. TimedOut is set if the
+// eviction call never succeeded (a terminal error, or transient errors until MaxPodEvictionTime elapsed), or if we
+// gave up waiting for the pod to actually disappear from the node before MaxPodEvictionTime plus
+// PodEvictionHeadroom elapsed.
+type EvictionResult struct {
+	Pod      *apiv1.Pod
+	TimedOut bool
+	Err      error
+	// Blocked is true if the pod was never submitted for eviction because it carries a do-not-evict annotation and
+	// DoNotEvictPolicy is Block. WaitAndEvict and Force both eventually evict rather than setting this.
+	Blocked bool
+	// Skipped is true if EvictionNodeFitCheck is enabled and the pod was never submitted for eviction because no
+	// other node in the cluster snapshot could currently fit it.
+	Skipped bool
+	// Reason explains why Skipped is true.
+	Reason string
+}
+
+// WasEvictionSuccessful returns true if Err is nil. It returns false both when the eviction call failed and when
+// the call succeeded but confirming the pod's removal kept failing with an error; a plain confirmation timeout
+// (TimedOut with no Err) still counts as successful.
+func (e EvictionResult) WasEvictionSuccessful() bool {
+	return e.Err == nil
+}
+
+// Evictor evicts pods from nodes. All non-DaemonSet pod evictions it issues, across every node being drained
+// concurrently, are funneled through a single shared, bounded work queue: workers pop {pod, deadline} items,
+// attempt the eviction, and re-enqueue transient failures (429s, PDB violations, timeouts) with exponential backoff
+// rather than retrying each pod in its own tight per-drain loop. This keeps a large scale-down from
+// thundering-herding the API server. DaemonSet pod evictions do not go through the queue: each is issued directly
+// from its own goroutine.
+type Evictor struct {
+	EvictionRetryTime          time.Duration
+	DsEvictionRetryTime        time.Duration
+	DsEvictionEmptyNodeTimeout time.Duration
+	PodEvictionHeadroom        time.Duration
+	// MaxConcurrentEvictions bounds the number of non-DaemonSet pod evictions in flight at once across all nodes
+	// sharing this Evictor; DaemonSet pod evictions are not counted. Zero means DefaultMaxConcurrentEvictions.
+	MaxConcurrentEvictions int
+
+	// DoNotEvictPolicy decides how pods carrying a do-not-evict annotation (see DoNotEvictAnnotations) are
+	// handled. Defaults to DoNotEvictBlock. There's no --do-not-evict-policy flag wiring this up to
+	// config.AutoscalingOptions in this checkout; operators need to set it on the Evictor they construct.
+	DoNotEvictPolicy DoNotEvictPolicy
+	// DoNotEvictAnnotations lists additional "<key>=false" pod annotations that mark a pod as not safe to evict,
+	// on top of the built-in cluster-autoscaler.kubernetes.io/safe-to-evict. Like DoNotEvictPolicy, there's no
+	// --do-not-evict-annotations flag wiring this up in this checkout.
+	DoNotEvictAnnotations []string
+	// DoNotEvictGracePeriod is how long DoNotEvictPolicy WaitAndEvict waits for a do-not-evict annotation to
+	// clear before evicting anyway, and how long DoNotEvictForce waits before evicting unconditionally.
+	DoNotEvictGracePeriod time.Duration
+
+	// EmitDisruptionTargetCondition makes CA patch a DisruptionTarget pod condition (KEP-3329) onto every pod
+	// before evicting it. Off by default until 1.26+ clusters, which understand the condition, are common.
+	EmitDisruptionTargetCondition bool
+
+	// PostEvictionKillPolicy decides what, if anything, happens to a pod that successfully accepted an eviction
+	// but is still present once confirmRemoval gives up waiting for it to actually disappear.
+	PostEvictionKillPolicy PostEvictionKillPolicy
+
+	// EvictionNodeFitCheck makes DrainNodeWithPods skip evicting a pod if no other node in the cluster snapshot
+	// can currently fit it, so scale-down doesn't immediately trigger another scale-up for the same pod. There's
+	// no --eviction-node-fit-check flag wiring this up to config.AutoscalingOptions in this checkout; operators
+	// need to set it on the Evictor they construct.
+	EvictionNodeFitCheck bool
+
+	// Backend issues the actual eviction call. Defaults to PolicyV1Beta1Backend if nil. There's no
+	// --eviction-backend/--eviction-webhook-url flag wiring a non-default Backend (e.g. WebhookBackend) up to
+	// config.AutoscalingOptions in this checkout; operators need to set it on the Evictor they construct.
+	Backend EvictionBackend
+
+	queueOnce sync.Once
+	queue     *evictionQueue
+}
+
+func (e *Evictor) ensureQueue() *evictionQueue {
+	e.queueOnce.Do(func() {
+		workers := e.MaxConcurrentEvictions
+		if workers <= 0 {
+			workers = DefaultMaxConcurrentEvictions
+		}
+		e.queue = newEvictionQueue(workers, e.EvictionRetryTime, maxEvictionBackoff)
+	})
+	return e.queue
+}
+
+// backend returns e.Backend, falling back to PolicyV1Beta1Backend if none was configured.
+func (e *Evictor) backend() EvictionBackend {
+	if e.Backend != nil {
+		return e.Backend
+	}
+	return PolicyV1Beta1Backend{}
+}
+
+// DrainNodeWithPods evicts `pods` from `node`, plus `daemonSetPods` on a best-effort basis, and waits for each
+// regular pod to either be confirmed gone, hit a terminal eviction error, or exceed MaxPodEvictionTime plus
+// PodEvictionHeadroom. It returns a per-pod EvictionResult and a combined error listing any pod that didn't make
+// it all the way through.
+func (e *Evictor) DrainNodeWithPods(ctx *acontext.AutoscalingContext, node *apiv1.Node, pods, daemonSetPods []*apiv1.Pod) (map[string]EvictionResult, error) {
+	queue := e.ensureQueue()
+
+	now := time.Now()
+	evictDeadline := now.Add(ctx.MaxPodEvictionTime)
+	confirmDeadline := evictDeadline.Add(e.PodEvictionHeadroom)
+
+	empty := len(pods) == 0
+
+	// DaemonSet pods are evicted best-effort: a stuck DaemonSet controller shouldn't block the node from being
+	// removed, so we fire these off and don't track their outcome.
+	for _, pod := range daemonSetPods {
+		pod := pod
+		go func() {
+			e.maybeEmitDisruptionTargetCondition(ctx, pod, node, empty)
+			if err := evictOnce(ctx, e.backend(), pod, node, gracePeriodFor(ctx, pod)); err != nil {
+				klog.Warningf("Failed to evict DaemonSet pod %s/%s while draining node %s: %v", pod.Namespace, pod.Name, node.Name, err)
+			}
+		}()
+	}
+
+	// Submitting a pod can itself block for up to DoNotEvictGracePeriod (handleDoNotEvict's WaitAndEvict/Force
+	// wait) or take a snapshot lookup (the node-fit check), so each pod is submitted from its own goroutine
+	// rather than in this loop directly: a do-not-evict pod waiting out its grace period must not hold up
+	// enqueuing every pod after it, or the bounded, concurrent eviction queue request #1 built buys nothing.
+	results := make(map[string]EvictionResult, len(pods))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		pod := pod
+		wg.Add(1)
+		go func() {
+			if blocked := e.handleDoNotEvict(ctx, node, pod); blocked {
+				defer wg.Done()
+				mu.Lock()
+				results[pod.Name] = EvictionResult{Pod: pod, Blocked: true}
+				mu.Unlock()
+				doNotEvictStalledDrains.Inc()
+				return
+			}
+			if e.EvictionNodeFitCheck && !podFitsAnyOtherNode(ctx, node, pod) {
+				defer wg.Done()
+				mu.Lock()
+				results[pod.Name] = EvictionResult{Pod: pod, Skipped: true, Reason: "no fitting node"}
+				mu.Unlock()
+				return
+			}
+			e.maybeEmitDisruptionTargetCondition(ctx, pod, node, empty)
+			queue.enqueue(&evictionItem{
+				ctx:             ctx,
+				node:            node,
+				pod:             pod,
+				evictDeadline:   evictDeadline,
+				confirmDeadline: confirmDeadline,
+				killPolicy:      e.PostEvictionKillPolicy,
+				backend:         e.backend(),
+				gracePeriod:     gracePeriodFor(ctx, pod),
+				done: func(outcome evictionOutcome) {
+					defer wg.Done()
+					mu.Lock()
+					results[pod.Name] = EvictionResult{Pod: pod, Err: outcome.err, TimedOut: outcome.timedOut}
+					mu.Unlock()
+				},
+			})
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	for name, res := range results {
+		if !res.WasEvictionSuccessful() || res.TimedOut || res.Blocked || res.Skipped {
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) == 0 {
+		return results, nil
+	}
+	sort.Strings(failed)
+	return results, fmt.Errorf("following pods failed to evict or confirm removal from node %s: %s", node.Name, strings.Join(failed, ","))
+}
+
+// EvictDaemonSetPods creates eviction objects for the DaemonSet pods on an empty node, so that CA doesn't have to
+// wait for the DaemonSet controller to replace them elsewhere before the node can be deleted.
+func (e *Evictor) EvictDaemonSetPods(ctx *acontext.AutoscalingContext, node *apiv1.Node, timeNow time.Time) error {
+	nodeInfo, err := ctx.ClusterSnapshot.NodeInfos().Get(node.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get node info for %s: %v", node.Name, err)
+	}
+
+	var dsPods []*apiv1.Pod
+	for _, podInfo := range nodeInfo.Pods {
+		pod := podInfo.Pod
+		if !pod_util.IsDaemonSetPod(pod) {
+			continue
+		}
+		if shouldEvictDaemonSetPod(pod, ctx.AutoscalingOptions.DaemonSetEvictionForEmptyNodes) {
+			dsPods = append(dsPods, pod)
+		}
+	}
+
+	deadline := time.Now().Add(e.DsEvictionEmptyNodeTimeout)
+	results := make(chan error, len(dsPods))
+	for _, pod := range dsPods {
+		pod := pod
+		go func() {
+			e.maybeEmitDisruptionTargetCondition(ctx, pod, node, true)
+			results <- evictDaemonSetPod(ctx, e.backend(), pod, node, deadline, e.DsEvictionRetryTime, gracePeriodFor(ctx, pod))
+		}()
+	}
+	var errs []string
+	for range dsPods {
+		if err := <-results; err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("following DaemonSet pod failed to evict on the %s node: %s", node.Name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// shouldEvictDaemonSetPod decides whether a DaemonSet pod should be evicted: a per-pod
+// daemonset.EnableDsEvictionKey annotation always wins, otherwise the caller-supplied default applies.
+func shouldEvictDaemonSetPod(pod *apiv1.Pod, evictByDefault bool) bool {
+	if v, ok := pod.Annotations[daemonset.EnableDsEvictionKey]; ok {
+		return v == "true"
+	}
+	return evictByDefault
+}
+
+// evictDaemonSetPod makes at most two attempts to evict a DaemonSet pod: if the first fails and deadline hasn't
+// passed, it waits retryTime and tries once more. Each attempt gives up as soon as deadline passes, even if the
+// eviction call is still hanging.
+func evictDaemonSetPod(ctx *acontext.AutoscalingContext, backend EvictionBackend, pod *apiv1.Pod, node *apiv1.Node, deadline time.Time, retryTime time.Duration, gracePeriodSeconds int64) error {
+	attempt := func() error {
+		errCh := make(chan error, 1)
+		go func() { errCh <- evictOnce(ctx, backend, pod, node, gracePeriodSeconds) }()
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		select {
+		case err := <-errCh:
+			return err
+		case <-time.After(remaining):
+			return fmt.Errorf("failed to create DaemonSet eviction for %s: timed out waiting for the eviction API to respond", pod.Name)
+		}
+	}
+
+	err := attempt()
+	if err == nil {
+		return nil
+	}
+	if time.Now().Before(deadline) {
+		time.Sleep(retryTime)
+		if err2 := attempt(); err2 == nil {
+			return nil
+		} else {
+			err = err2
+		}
+	}
+	return fmt.Errorf("failed to evict DaemonSet pod %s: %v", pod.Name, err)
+}
+
+// podsToEvict splits the pods currently assigned to nodeName in the cluster snapshot into DaemonSet and
+// non-DaemonSet pods, dropping mirror pods entirely since they can't be evicted through the API server.
+func podsToEvict(ctx *acontext.AutoscalingContext, nodeName string) (dsPods, nonDsPods []*apiv1.Pod, err error) {
+	nodeInfo, err := ctx.ClusterSnapshot.NodeInfos().Get(nodeName)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, podInfo := range nodeInfo.Pods {
+		pod := podInfo.Pod
+		if pod_util.IsMirrorPod(pod) {
+			continue
+		}
+		if !pod_util.IsDaemonSetPod(pod) {
+			nonDsPods = append(nonDsPods, pod)
+			continue
+		}
+		if shouldEvictDaemonSetPod(pod, ctx.AutoscalingOptions.DaemonSetEvictionForOccupiedNodes) {
+			dsPods = append(dsPods, pod)
+		}
+	}
+	return dsPods, nonDsPods, nil
+}
+
+// evictOnce issues a single eviction call for pod through backend.
+func evictOnce(ctx *acontext.AutoscalingContext, backend EvictionBackend, pod *apiv1.Pod, node *apiv1.Node, gracePeriodSeconds int64) error {
+	return backend.Evict(ctx, pod, node, gracePeriodSeconds)
+}
+
+// gracePeriodFor returns the grace period to request for evicting pod: the pod's own
+// terminationGracePeriodSeconds if set, otherwise MaxGracefulTerminationSec.
+func gracePeriodFor(ctx *acontext.AutoscalingContext, pod *apiv1.Pod) int64 {
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		return int64(*pod.Spec.TerminationGracePeriodSeconds)
+	}
+	return int64(ctx.MaxGracefulTerminationSec)
+}
+
+// isTerminalEvictionError reports whether err is a response that will never succeed on retry, so the eviction
+// queue shouldn't keep re-enqueueing the pod.
+func isTerminalEvictionError(err error) bool {
+	return errors.IsNotFound(err) || errors.IsForbidden(err)
+}
+
+// confirmRemoval polls the API server until pod is gone from node, has been rescheduled onto a different node, or
+// deadline passes, returning whether the removal was confirmed. If deadline passes because Get itself kept
+// failing (as opposed to simply finding the pod still on node), the last such error is returned so the caller can
+// surface it on the pod's EvictionResult instead of silently treating it as a plain timeout.
+func confirmRemoval(ctx *acontext.AutoscalingContext, node *apiv1.Node, pod *apiv1.Pod, deadline time.Time) (bool, error) {
+	var lastErr error
+	for {
+		current, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			lastErr = err
+		} else if current != nil && current.Spec.NodeName != node.Name {
+			return true, nil
+		}
+		if !time.Now().Before(deadline) {
+			return false, lastErr
+		}
+		time.Sleep(confirmationPollInterval)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+// EvictionBackend issues the actual eviction call for a pod, so that Evictor itself doesn't need to know whether
+// it's talking to the policy/v1beta1 API, the policy/v1 API, or something else entirely.
+type EvictionBackend interface {
+	Evict(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, node *apiv1.Node, gracePeriodSeconds int64) error
+}
+
+// PolicyV1Beta1Backend evicts pods through the policy/v1beta1 Eviction subresource, for clusters older than 1.22
+// where policy/v1 isn't available.
+type PolicyV1Beta1Backend struct{}
+
+// Evict implements EvictionBackend.
+func (PolicyV1Beta1Backend) Evict(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, _ *apiv1.Node, gracePeriodSeconds int64) error {
+	eviction := &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: &gracePeriodSeconds,
+		},
+	}
+	return ctx.ClientSet.PolicyV1beta1().Evictions(eviction.Namespace).Evict(context.TODO(), eviction)
+}
+
+// PolicyV1Backend evicts pods through the policy/v1 Eviction subresource, required on 1.26+ clusters where
+// policy/v1beta1 has been removed.
+type PolicyV1Backend struct{}
+
+// Evict implements EvictionBackend.
+func (PolicyV1Backend) Evict(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, _ *apiv1.Node, gracePeriodSeconds int64) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: &gracePeriodSeconds,
+		},
+	}
+	return ctx.ClientSet.PolicyV1().Evictions(eviction.Namespace).Evict(context.TODO(), eviction)
+}
+
+// WebhookBackend delegates the eviction decision to an external operator-supplied endpoint, e.g. a drain
+// coordinator or ticketing system, by POSTing {pod, node, reason} and treating any HTTP 2xx as success.
+type WebhookBackend struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+type webhookEvictionRequest struct {
+	Pod    *apiv1.Pod  `json:"pod"`
+	Node   *apiv1.Node `json:"node"`
+	Reason string      `json:"reason"`
+}
+
+// Evict implements EvictionBackend.
+func (w WebhookBackend) Evict(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, node *apiv1.Node, gracePeriodSeconds int64) error {
+	client := w.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	body, err := json.Marshal(webhookEvictionRequest{Pod: pod, Node: node, Reason: "cluster-autoscaler scale down"})
+	if err != nil {
+		return fmt.Errorf("failed to build webhook eviction request for pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook eviction request for pod %s/%s failed: %v", pod.Namespace, pod.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook eviction backend rejected pod %s/%s with status %s", pod.Namespace, pod.Name, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestPolicyV1Beta1BackendEvict(t *testing.T) {
+	pod := BuildTestPod("p1", 100, 0)
+	node := BuildTestNode("n1", 1000, 1000)
+
+	var created *policyv1beta1.Eviction
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		created = action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		return true, nil, nil
+	})
+
+	ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, PolicyV1Beta1Backend{}.Evict(&ctx, pod, node, 30))
+	if assert.NotNil(t, created) {
+		assert.Equal(t, pod.Name, created.Name)
+		assert.Equal(t, pod.Namespace, created.Namespace)
+		assert.Equal(t, int64(30), *created.DeleteOptions.GracePeriodSeconds)
+	}
+}
+
+func TestPolicyV1BackendEvict(t *testing.T) {
+	pod := BuildTestPod("p1", 100, 0)
+	node := BuildTestNode("n1", 1000, 1000)
+
+	var created *policyv1.Eviction
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		created = action.(core.CreateAction).GetObject().(*policyv1.Eviction)
+		return true, nil, nil
+	})
+
+	ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, PolicyV1Backend{}.Evict(&ctx, pod, node, 30))
+	if assert.NotNil(t, created) {
+		assert.Equal(t, pod.Name, created.Name)
+		assert.Equal(t, pod.Namespace, created.Namespace)
+		assert.Equal(t, int64(30), *created.DeleteOptions.GracePeriodSeconds)
+	}
+}
+
+func TestWebhookBackendEvict(t *testing.T) {
+	pod := BuildTestPod("p1", 100, 0)
+	node := BuildTestNode("n1", 1000, 1000)
+
+	for tn, tc := range map[string]struct {
+		status  int
+		wantErr bool
+	}{
+		"2xx is success":          {status: http.StatusOK, wantErr: false},
+		"202 Accepted is success": {status: http.StatusAccepted, wantErr: false},
+		"4xx is an error":         {status: http.StatusForbidden, wantErr: true},
+		"5xx is an error":         {status: http.StatusInternalServerError, wantErr: true},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			var gotBody webhookEvictionRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			backend := WebhookBackend{URL: server.URL}
+			err := backend.Evict(nil, pod, node, 30)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, pod.Name, gotBody.Pod.Name)
+			assert.Equal(t, node.Name, gotBody.Node.Name)
+		})
+	}
+}
+
+func TestWebhookBackendDefaultsHTTPClient(t *testing.T) {
+	backend := WebhookBackend{URL: "http://127.0.0.1:0"}
+	assert.Nil(t, backend.HTTPClient)
+	assert.Error(t, backend.Evict(nil, BuildTestPod("p1", 100, 0), BuildTestNode("n1", 1000, 1000), 30))
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+// evictionOutcome is what an evictionItem's done callback is invoked with once the item leaves the queue for good.
+type evictionOutcome struct {
+	err      error
+	timedOut bool
+}
+
+// evictionItem is one {pod, deadline} unit of work submitted to the shared eviction queue.
+type evictionItem struct {
+	ctx  *acontext.AutoscalingContext
+	node *apiv1.Node
+	pod  *apiv1.Pod
+	// evictDeadline bounds how long we keep retrying a transiently-failing eviction call.
+	evictDeadline time.Time
+	// confirmDeadline bounds how long we wait, after a successful eviction call, for the pod to actually
+	// disappear. It's always >= evictDeadline since it also covers PodEvictionHeadroom.
+	confirmDeadline time.Time
+	// killPolicy decides whether a pod still present once confirmDeadline passes gets force-deleted.
+	killPolicy PostEvictionKillPolicy
+	// backend issues the actual eviction call.
+	backend EvictionBackend
+	// gracePeriod is the grace period, in seconds, requested for this pod's eviction.
+	gracePeriod int64
+	done        func(evictionOutcome)
+}
+
+// evictionQueue funnels every non-DaemonSet pod eviction, across every node draining concurrently through a single
+// Evictor, through a bounded pool of workers. Transient failures are re-enqueued with exponential backoff instead of being
+// retried in a tight per-pod loop, so a large scale-down doesn't thundering-herd the API server.
+type evictionQueue struct {
+	items   chan *evictionItem
+	backoff *evictionBackoff
+}
+
+func newEvictionQueue(workers int, backoffBase, backoffCap time.Duration) *evictionQueue {
+	q := &evictionQueue{
+		items:   make(chan *evictionItem, 4096),
+		backoff: newEvictionBackoff(backoffBase, backoffCap),
+	}
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+	return q
+}
+
+func (q *evictionQueue) enqueue(item *evictionItem) {
+	q.items <- item
+}
+
+// boundedEvictOnce runs a single eviction attempt for item, abandoning it once maxSingleEvictionAttempt elapses so
+// a hung backend call doesn't block this worker from processing the rest of the queue.
+func boundedEvictOnce(item *evictionItem) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- evictOnce(item.ctx, item.backend, item.pod, item.node, item.gracePeriod) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(maxSingleEvictionAttempt):
+		return fmt.Errorf("eviction attempt for pod %s/%s timed out after %s", item.pod.Namespace, item.pod.Name, maxSingleEvictionAttempt)
+	}
+}
+
+// maxSingleEvictionAttempt bounds how long a single eviction attempt may run before the worker gives up on it and
+// treats it as a transient failure to retry, so a slow or hung EvictionBackend (e.g. a webhook endpoint) can't tie
+// up one of the queue's limited workers indefinitely.
+const maxSingleEvictionAttempt = 10 * time.Second
+
+func (q *evictionQueue) worker() {
+	for item := range q.items {
+		err := boundedEvictOnce(item)
+		if err == nil {
+			q.backoff.forget(item.pod)
+			confirmed, confirmErr := confirmRemoval(item.ctx, item.node, item.pod, item.confirmDeadline)
+			timedOut := !confirmed
+			if timedOut {
+				killStuckPod(item.ctx, item.pod, item.killPolicy)
+			}
+			item.done(evictionOutcome{err: confirmErr, timedOut: timedOut})
+			continue
+		}
+		if isTerminalEvictionError(err) {
+			q.backoff.forget(item.pod)
+			item.done(evictionOutcome{err: err, timedOut: true})
+			continue
+		}
+		if !time.Now().Before(item.evictDeadline) {
+			q.backoff.forget(item.pod)
+			item.done(evictionOutcome{err: err, timedOut: true})
+			continue
+		}
+		delay := q.backoff.next(item.pod)
+		item := item
+		time.AfterFunc(delay, func() { q.enqueue(item) })
+	}
+}
+
+// evictionBackoff computes a per-pod exponential, jittered backoff so repeated failures for one pod don't affect
+// the retry cadence of any other pod sharing the queue.
+type evictionBackoff struct {
+	base time.Duration
+	cap  time.Duration
+
+	mu       sync.Mutex
+	failures map[types.UID]int
+}
+
+func newEvictionBackoff(base, cap time.Duration) *evictionBackoff {
+	return &evictionBackoff{base: base, cap: cap, failures: map[types.UID]int{}}
+}
+
+func (b *evictionBackoff) next(pod *apiv1.Pod) time.Duration {
+	b.mu.Lock()
+	n := b.failures[pod.UID]
+	b.failures[pod.UID] = n + 1
+	b.mu.Unlock()
+
+	if b.base <= 0 {
+		// No base backoff was configured: retry immediately instead of falling into the overflow handling
+		// below, which would otherwise max a disabled backoff straight out to b.cap.
+		return 0
+	}
+
+	delay := b.base << uint(n)
+	if delay <= 0 || delay > b.cap {
+		// delay <= 0 here means the shift overflowed time.Duration, not that backoff is disabled (b.base > 0
+		// is already guaranteed above).
+		delay = b.cap
+	}
+	// Full jitter: a random delay in [0, delay], so many pods backing off at once don't retry in lockstep.
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+func (b *evictionBackoff) forget(pod *apiv1.Pod) {
+	b.mu.Lock()
+	delete(b.failures, pod.UID)
+	b.mu.Unlock()
+}
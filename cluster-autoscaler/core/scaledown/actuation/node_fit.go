@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+)
+
+// podFitsAnyOtherNode reports whether at least one node in the cluster snapshot, other than excluding, currently
+// has room for pod given its resource requests, node selectors/affinity, and taints/tolerations. It's used as a
+// last check before evicting a pod for scale-down, so we don't evict something that will just go Pending and
+// immediately trigger a scale-up again.
+func podFitsAnyOtherNode(ctx *acontext.AutoscalingContext, excluding *apiv1.Node, pod *apiv1.Pod) bool {
+	nodeInfos, err := ctx.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		// If we can't even list nodes, don't block eviction on a check we can't perform.
+		return true
+	}
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node == nil || node.Name == excluding.Name {
+			continue
+		}
+		if !simulator.IsNodeReadyAndSchedulable(node) {
+			continue
+		}
+		if err := simulator.CheckPredicates(ctx.ClusterSnapshot, pod, node.Name); err == nil {
+			return true
+		}
+	}
+	return false
+}
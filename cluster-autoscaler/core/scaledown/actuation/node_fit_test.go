@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestPodFitsAnyOtherNode(t *testing.T) {
+	pod := BuildTestPod("p1", 500, 0)
+
+	for tn, tc := range map[string]struct {
+		excludingName string
+		notReadyName  string
+		nodes         []*apiv1.Node
+		want          bool
+	}{
+		"fits on another node with room": {
+			excludingName: "full-node",
+			nodes: []*apiv1.Node{
+				BuildTestNode("full-node", 100, 1000),
+				BuildTestNode("roomy-node", 1000, 1000),
+			},
+			want: true,
+		},
+		"only node with room is the one being drained": {
+			excludingName: "roomy-node",
+			nodes: []*apiv1.Node{
+				BuildTestNode("roomy-node", 1000, 1000),
+				BuildTestNode("full-node", 100, 1000),
+			},
+			want: false,
+		},
+		"no other nodes at all": {
+			excludingName: "only-node",
+			nodes: []*apiv1.Node{
+				BuildTestNode("only-node", 1000, 1000),
+			},
+			want: false,
+		},
+		"a node with room is skipped if it's not ready": {
+			excludingName: "full-node",
+			notReadyName:  "not-ready-node",
+			nodes: []*apiv1.Node{
+				BuildTestNode("full-node", 100, 1000),
+				BuildTestNode("not-ready-node", 1000, 1000),
+			},
+			want: false,
+		},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			snapshot := simulator.NewBasicClusterSnapshot()
+			var excluding *apiv1.Node
+			for _, node := range tc.nodes {
+				SetNodeReadyState(node, node.Name != tc.notReadyName, time.Time{})
+				if node.Name == tc.excludingName {
+					excluding = node
+				}
+				assert.NoError(t, snapshot.AddNode(node))
+			}
+
+			ctx := &acontext.AutoscalingContext{ClusterSnapshot: snapshot}
+			got := podFitsAnyOtherNode(ctx, excluding, pod)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+// PostEvictionKillPolicy decides what happens to a pod that accepted an eviction but is still present once
+// confirmRemoval gives up waiting for it to actually go away, e.g. because it's ignoring SIGTERM.
+type PostEvictionKillPolicy string
+
+const (
+	// PostEvictionKillNone leaves the pod alone; the node deletion that follows will take it down anyway.
+	PostEvictionKillNone PostEvictionKillPolicy = "None"
+	// PostEvictionKillDeleteAfterGrace force-deletes the pod once confirmRemoval's deadline passes.
+	PostEvictionKillDeleteAfterGrace PostEvictionKillPolicy = "DeleteAfterGrace"
+	// PostEvictionKillForceDelete is an alias for PostEvictionKillDeleteAfterGrace kept for explicitness in
+	// --post-eviction-kill-policy; both force-delete at the same point today.
+	PostEvictionKillForceDelete PostEvictionKillPolicy = "ForceDelete"
+)
+
+var podsForceDeleted = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "cluster_autoscaler",
+	Name:      "pods_force_deleted_total",
+	Help:      "Number of pods that accepted an eviction but never disappeared, and were force-deleted as a result.",
+})
+
+func init() {
+	prometheus.MustRegister(podsForceDeleted)
+}
+
+// killStuckPod force-deletes pod, with zero grace period and background propagation, if policy calls for it. It
+// logs the owning controller so operators can tell which workload got the hard kill.
+func killStuckPod(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, policy PostEvictionKillPolicy) {
+	if policy == PostEvictionKillNone || policy == "" {
+		return
+	}
+
+	gracePeriod := int64(0)
+	propagation := metav1.DeletePropagationBackground
+	err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{
+		GracePeriodSeconds: &gracePeriod,
+		PropagationPolicy:  &propagation,
+	})
+	if err != nil {
+		klog.Errorf("Failed to force-delete stuck pod %s/%s (owner: %s): %v", pod.Namespace, pod.Name, owningController(pod), err)
+		return
+	}
+	podsForceDeleted.Inc()
+	klog.Warningf("Force-deleted pod %s/%s (owner: %s) after it stayed around past its eviction deadline", pod.Namespace, pod.Name, owningController(pod))
+}
+
+// owningController returns a human-readable "Kind/Name" for pod's controlling owner reference, or "none" if it
+// has no controller (e.g. a bare pod).
+func owningController(pod *apiv1.Pod) string {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller {
+			return ref.Kind + "/" + ref.Name
+		}
+	}
+	return "none"
+}
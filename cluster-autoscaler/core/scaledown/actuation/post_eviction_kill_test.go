@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestKillStuckPod(t *testing.T) {
+	for tn, tc := range map[string]struct {
+		policy      PostEvictionKillPolicy
+		wantDeleted bool
+	}{
+		"None leaves the pod alone": {
+			policy:      PostEvictionKillNone,
+			wantDeleted: false,
+		},
+		"unset policy leaves the pod alone": {
+			policy:      "",
+			wantDeleted: false,
+		},
+		"DeleteAfterGrace force-deletes the pod": {
+			policy:      PostEvictionKillDeleteAfterGrace,
+			wantDeleted: true,
+		},
+		"ForceDelete force-deletes the pod": {
+			policy:      PostEvictionKillForceDelete,
+			wantDeleted: true,
+		},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			pod := BuildTestPod("p1", 100, 0)
+
+			fakeClient := &fake.Clientset{}
+			ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, fakeClient, nil, nil, nil, nil)
+			assert.NoError(t, err)
+
+			killStuckPod(&ctx, pod, tc.policy)
+
+			deleted := false
+			for _, action := range fakeClient.Actions() {
+				if deleteAction, ok := action.(core.DeleteAction); ok && deleteAction.GetResource().Resource == "pods" {
+					deleted = true
+				}
+			}
+			assert.Equal(t, tc.wantDeleted, deleted)
+		})
+	}
+}
+
+func TestOwningController(t *testing.T) {
+	withController := BuildTestPod("p1", 100, 0)
+	withController.OwnerReferences = GenerateOwnerReferences("rs-1", "ReplicaSet", "apps/v1", "some-uid")
+	assert.Equal(t, "ReplicaSet/rs-1", owningController(withController))
+
+	assert.Equal(t, "none", owningController(BuildTestPod("p2", 100, 0)))
+}